@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package loader
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/cilium/cilium/pkg/command/exec"
+)
+
+// bpfCacheDir is the subdirectory of option.Config.StateDir that holds
+// cached compiler outputs, keyed by the hash of their inputs.
+const bpfCacheDir = "bpfcache"
+
+var (
+	compilerVersionMu     sync.Mutex
+	compilerVersionCached string
+)
+
+// getCompilerVersion returns the output of "clang --version", fetching it
+// at most once per agent lifetime since it cannot change without a restart.
+// Unlike a sync.Once, a failed fetch (e.g. a transient exec error) is not
+// cached, so the next compile simply retries instead of disabling the
+// compile cache for the rest of the agent's lifetime.
+func getCompilerVersion(ctx context.Context, scopedLog *logrus.Entry) (string, error) {
+	compilerVersionMu.Lock()
+	defer compilerVersionMu.Unlock()
+
+	if compilerVersionCached != "" {
+		return compilerVersionCached, nil
+	}
+
+	versionCmd := exec.CommandContext(ctx, compiler, "--version")
+	out, err := versionCmd.CombinedOutput(scopedLog, true)
+	if err != nil {
+		return "", err
+	}
+
+	compilerVersionCached = string(out)
+	return compilerVersionCached, nil
+}
+
+// dependencyFiles asks the compiler which files would be read while
+// preprocessing prog (the source itself plus every transitively included
+// header), so they can be folded into the cache key. This mirrors what the
+// real compile() invocation below would touch.
+func dependencyFiles(ctx context.Context, compileArgs []string, prog *progInfo, dir *directoryInfo) ([]string, error) {
+	// compileArgs always ends in "-c <src> -o -" (see compile()); asking
+	// for a dependency list with -M/-MF while those are still present
+	// makes the compiler reject the invocation outright, so strip them
+	// before appending the dependency-scan flags.
+	baseArgs := stripCompileOutputFlags(compileArgs)
+
+	args := make([]string, 0, len(baseArgs)+4)
+	args = append(args, baseArgs...)
+	args = append(args, "-M", "-MF", "-", filepath.Join(dir.Library, prog.Source))
+
+	depCmd, cancel := exec.WithCancel(ctx, compiler, args...)
+	defer cancel()
+
+	out, err := depCmd.CombinedOutput(log, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine dependencies of %s: %w", prog.Source, err)
+	}
+
+	return parseMakeDepends(string(out)), nil
+}
+
+// stripCompileOutputFlags drops the "-c <src> -o -" suffix compile() adds
+// to compileArgs, since the dependency scan needs to pass its own "-M -MF"
+// output flags instead.
+func stripCompileOutputFlags(args []string) []string {
+	for i, a := range args {
+		if a == "-c" {
+			return args[:i]
+		}
+	}
+	return args
+}
+
+// parseMakeDepends extracts the file list out of the Makefile-style
+// dependency rule produced by "clang -M", e.g.:
+//
+//	bpf_lxc.o: bpf_lxc.c lib/common.h lib/eth.h \
+//	  lib/dbg.h
+func parseMakeDepends(depFile string) []string {
+	depFile = strings.ReplaceAll(depFile, "\\\n", " ")
+	scanner := bufio.NewScanner(strings.NewReader(depFile))
+	var files []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, ":"); idx >= 0 {
+			line = line[idx+1:]
+		}
+		for _, field := range strings.Fields(line) {
+			files = append(files, field)
+		}
+	}
+	return files
+}
+
+// hashFile returns the hex-encoded SHA-256 digest of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheKey computes a stable content hash for the compilation described by
+// compileArgs and deps: the compiler version, the sorted argument list, and
+// the digest of every dependency file. Because the arguments are sorted,
+// their order is not part of the key: two invocations that pass the same
+// arguments in a different order get the same key.
+func cacheKey(compilerVersion string, compileArgs []string, deps []string) (string, error) {
+	sortedArgs := make([]string, len(compileArgs))
+	copy(sortedArgs, compileArgs)
+	sort.Strings(sortedArgs)
+
+	sortedDeps := make([]string, len(deps))
+	copy(sortedDeps, deps)
+	sort.Strings(sortedDeps)
+
+	h := sha256.New()
+	fmt.Fprintln(h, compilerVersion)
+	for _, arg := range sortedArgs {
+		fmt.Fprintln(h, arg)
+	}
+	for _, dep := range sortedDeps {
+		digest, err := hashFile(dep)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash dependency %s: %w", dep, err)
+		}
+		fmt.Fprintln(h, dep, digest)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheEntryPath returns the on-disk location of the cached artifact for
+// key, sharded by its first two hex characters to keep any one cache
+// directory from accumulating too many entries.
+func cacheEntryPath(cacheRoot, key, ext string) string {
+	return filepath.Join(cacheRoot, key[:2], key+"."+ext)
+}
+
+// lookupCacheEntry returns the path of a cached artifact if present.
+func lookupCacheEntry(cacheRoot, key, ext string) (string, bool) {
+	path := cacheEntryPath(cacheRoot, key, ext)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// installCacheEntry makes dst available by hardlinking it from the cache
+// entry, falling back to a copy if the cache lives on a different
+// filesystem than dst.
+func installCacheEntry(cachedPath, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(cachedPath, dst); err == nil {
+		return nil
+	}
+	return copyFile(cachedPath, dst)
+}
+
+// storeCacheEntry publishes src into the cache under key, using a
+// write-to-temp-then-rename so concurrent readers never observe a partial
+// file.
+func storeCacheEntry(cacheRoot, key, ext, src string) error {
+	dir := filepath.Join(cacheRoot, key[:2])
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := copyFileTo(src, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(dir, key+"."+ext))
+}
+
+func copyFile(src, dst string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return copyFileTo(src, out)
+}
+
+func copyFileTo(src string, dst *os.File) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(dst, in)
+	return err
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripCompileOutputFlags(t *testing.T) {
+	args := []string{"-O2", "--target=bpf", "-c", "bpf_lxc.c", "-o", "-"}
+	require.Equal(t, []string{"-O2", "--target=bpf"}, stripCompileOutputFlags(args))
+
+	// No "-c" present: nothing to strip.
+	require.Equal(t, []string{"-O2", "--target=bpf"}, stripCompileOutputFlags([]string{"-O2", "--target=bpf"}))
+}
+
+// TestCacheHitEndToEnd exercises storeCacheEntry -> lookupCacheEntry ->
+// installCacheEntry the way compile() does: populate the cache once, then
+// confirm a later compile() invocation with the same key can be served
+// entirely from disk instead of invoking the compiler again.
+func TestCacheHitEndToEnd(t *testing.T) {
+	cacheRoot := t.TempDir()
+	outDir := t.TempDir()
+
+	key, err := cacheKey("clang version 1", []string{"-O2", "-mcpu=v2"}, nil)
+	require.NoError(t, err)
+
+	// Nothing cached yet.
+	_, ok := lookupCacheEntry(cacheRoot, key, string(outputObject))
+	require.False(t, ok)
+
+	// Simulate the object a real compiler invocation would have produced.
+	compiled := filepath.Join(outDir, "bpf_lxc.o")
+	require.NoError(t, os.WriteFile(compiled, []byte("fake ELF contents"), 0644))
+
+	require.NoError(t, storeCacheEntry(cacheRoot, key, string(outputObject), compiled))
+
+	cachedPath, ok := lookupCacheEntry(cacheRoot, key, string(outputObject))
+	require.True(t, ok)
+
+	dst := filepath.Join(outDir, "bpf_lxc_from_cache.o")
+	require.NoError(t, installCacheEntry(cachedPath, dst))
+
+	got, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, "fake ELF contents", string(got))
+}
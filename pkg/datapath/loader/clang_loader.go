@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package loader
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// clangLoader implements Loader on top of the existing clang/LLVM compile
+// pipeline in compile.go. It is the default and, today, the only loader
+// capable of compiling a datapath program from source.
+type clangLoader struct{}
+
+func newClangLoader() *clangLoader {
+	return &clangLoader{}
+}
+
+func (c *clangLoader) CompileDatapath(ctx context.Context, dirs *directoryInfo, isHost bool, logger *logrus.Entry) error {
+	return compileDatapath(ctx, dirs, isHost, logger)
+}
+
+func (c *clangLoader) CompileTemplate(ctx context.Context, out string, isHost bool) error {
+	return compileTemplate(ctx, out, isHost)
+}
+
+func (c *clangLoader) CompileNetwork(ctx context.Context) error {
+	return compileNetwork(ctx)
+}
+
+func (c *clangLoader) CompileOverlay(ctx context.Context, opts []string) error {
+	return compileOverlay(ctx, opts)
+}
+
+func (c *clangLoader) CompileWithOptions(ctx context.Context, src string, out string, opts []string) error {
+	return CompileWithOptions(ctx, src, out, opts)
+}
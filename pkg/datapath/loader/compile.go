@@ -12,12 +12,14 @@ import (
 	"io"
 	"os"
 	"path"
+	"runtime"
 	"sync"
 	"syscall"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/asm"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/cilium/cilium/pkg/command/exec"
 	"github.com/cilium/cilium/pkg/common"
@@ -221,6 +223,15 @@ func compile(ctx context.Context, prog *progInfo, dir *directoryInfo) (string, e
 		"-o", "-", // Always output to stdout
 	)
 
+	outputPath := path.Join(dir.Output, prog.Output)
+	cacheRoot := path.Join(option.Config.StateDir, bpfCacheDir)
+
+	cacheKeyForProg, hit := tryCacheHit(ctx, compileArgs, prog, dir, cacheRoot, outputPath)
+	if hit {
+		log.WithField("output", outputPath).Debugf("Compilation served from cache (%s)", cacheKeyForProg)
+		return outputPath, nil
+	}
+
 	log.WithFields(logrus.Fields{
 		"target": compiler,
 		"args":   compileArgs,
@@ -229,11 +240,14 @@ func compile(ctx context.Context, prog *progInfo, dir *directoryInfo) (string, e
 	compileCmd, cancelCompile := exec.WithCancel(ctx, compiler, compileArgs...)
 	defer cancelCompile()
 
-	output, err := os.Create(path.Join(dir.Output, prog.Output))
+	output, err := os.Create(outputPath)
 	if err != nil {
 		return "", err
 	}
 	defer output.Close()
+	// os/exec special-cases an *os.File Stdout by dup'ing its fd straight
+	// into the child, so the compiler writes directly to disk with no
+	// Go-side buffering to bound; there's nothing to stream through here.
 	compileCmd.Stdout = output
 
 	var compilerStderr bytes.Buffer
@@ -271,9 +285,62 @@ func compile(ctx context.Context, prog *progInfo, dir *directoryInfo) (string, e
 		}).Debugf("Compilation had peak RSS of %d bytes", maxRSS)
 	}
 
+	if cacheKeyForProg != "" {
+		if err := storeCacheEntry(cacheRoot, cacheKeyForProg, string(prog.OutputType), output.Name()); err != nil {
+			log.WithError(err).Debug("Failed to populate BPF compile cache")
+		}
+	}
+
+	if prog.OutputType == outputObject && EnableVerifierComplexityCheck {
+		if err := checkVerifierComplexity(ctx, verifierChecker(), output.Name(), GetBPFCPU(), verifierThresholds); err != nil {
+			log.WithFields(logrus.Fields{
+				"output": output.Name(),
+				"mcpu":   GetBPFCPU(),
+			}).WithError(err).Warn("Verifier complexity check failed")
+		}
+	}
+
 	return output.Name(), nil
 }
 
+// tryCacheHit computes the content-addressed cache key for this compile
+// invocation and, on a hit, installs the cached artifact at outputPath
+// directly so the caller can skip invoking the compiler altogether. It
+// always returns the computed key (empty if it could not be determined,
+// e.g. because the dependency scan failed) so the caller can populate the
+// cache with the result of a subsequent compile.
+func tryCacheHit(ctx context.Context, compileArgs []string, prog *progInfo, dir *directoryInfo, cacheRoot, outputPath string) (string, bool) {
+	compilerVersion, err := getCompilerVersion(ctx, log)
+	if err != nil {
+		log.WithError(err).Debug("Failed to determine compiler version for BPF compile cache")
+		return "", false
+	}
+
+	deps, err := dependencyFiles(ctx, compileArgs, prog, dir)
+	if err != nil {
+		log.WithError(err).Debug("Failed to determine dependencies for BPF compile cache")
+		return "", false
+	}
+
+	key, err := cacheKey(compilerVersion, compileArgs, deps)
+	if err != nil {
+		log.WithError(err).Debug("Failed to compute BPF compile cache key")
+		return "", false
+	}
+
+	cachedPath, ok := lookupCacheEntry(cacheRoot, key, string(prog.OutputType))
+	if !ok {
+		return key, false
+	}
+
+	if err := installCacheEntry(cachedPath, outputPath); err != nil {
+		log.WithError(err).Debug("Failed to install BPF compile cache entry, falling back to compiler")
+		return key, false
+	}
+
+	return key, true
+}
+
 // compileDatapath invokes the compiler and linker to create all state files for
 // the BPF datapath, with the primary target being the BPF ELF binary.
 //
@@ -281,6 +348,9 @@ func compile(ctx context.Context, prog *progInfo, dir *directoryInfo) (string, e
 // * Preprocessed C
 // * Assembly
 // * Object compiled with debug symbols
+//
+// In debug mode these additional outputs are compiled concurrently with the
+// release object, bounded by GOMAXPROCS, instead of serially.
 func compileDatapath(ctx context.Context, dirs *directoryInfo, isHost bool, logger *logrus.Entry) error {
 	scopedLog := logger.WithField(logfields.Debug, true)
 
@@ -293,14 +363,42 @@ func compileDatapath(ctx context.Context, dirs *directoryInfo, isHost bool, logg
 		compiler: string(compilerVersion),
 	}).Debug("Compiling datapath")
 
-	if option.Config.Debug {
-		// Write out assembly and preprocessing files for debugging purposes
-		progs := debugProgs
-		if isHost {
-			progs = debugHostProgs
+	// Compile the new program
+	prog := epProg
+	if isHost {
+		prog = hostEpProg
+	}
+
+	if !option.Config.Debug {
+		if _, err := compile(ctx, prog, dirs); err != nil {
+			// Only log an error here if the context was not canceled. This log message
+			// should only represent failures with respect to compiling the program.
+			if !errors.Is(err, context.Canceled) {
+				scopedLog.WithField(logfields.Params, logfields.Repr(prog)).WithError(err).Warn("JoinEP: Failed to compile")
+			}
+			return err
 		}
-		for _, p := range progs {
-			if _, err := compile(ctx, p, dirs); err != nil {
+		return nil
+	}
+
+	// In debug mode we additionally want the preprocessed C, the assembly,
+	// and a debug object alongside the release object. Each of those reruns
+	// the same 100k+ lines of headers through the preprocessor, so rather
+	// than pay that cost four times serially, run the four compiles
+	// concurrently, bounded by GOMAXPROCS so we don't oversubscribe the
+	// node with clang invocations.
+	debug := debugProgs
+	if isHost {
+		debug = debugHostProgs
+	}
+	allProgs := append(append([]*progInfo{}, debug...), prog)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.GOMAXPROCS(0))
+	for _, p := range allProgs {
+		p := p
+		g.Go(func() error {
+			if _, err := compile(gctx, p, dirs); err != nil {
 				// Only log an error here if the context was not canceled. This log message
 				// should only represent failures with respect to compiling the program.
 				if !errors.Is(err, context.Canceled) {
@@ -308,24 +406,11 @@ func compileDatapath(ctx context.Context, dirs *directoryInfo, isHost bool, logg
 				}
 				return err
 			}
-		}
+			return nil
+		})
 	}
 
-	// Compile the new program
-	prog := epProg
-	if isHost {
-		prog = hostEpProg
-	}
-	if _, err := compile(ctx, prog, dirs); err != nil {
-		// Only log an error here if the context was not canceled. This log message
-		// should only represent failures with respect to compiling the program.
-		if !errors.Is(err, context.Canceled) {
-			scopedLog.WithField(logfields.Params, logfields.Repr(prog)).WithError(err).Warn("JoinEP: Failed to compile")
-		}
-		return err
-	}
-
-	return nil
+	return g.Wait()
 }
 
 // CompileWithOptions compiles a BPF program generating an object file,
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+//go:build coreloader
+
+package loader
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/sirupsen/logrus"
+)
+
+// coreObjs holds the precompiled CO-RE ELFs produced by bpf2go for each of
+// the datapath entry points. They are generated from the same bpf/*.c
+// sources as the clang loader compiles on the fly, see coreobjs/README.md.
+// This file (and therefore the embed) is only built with the "coreloader"
+// tag, which is not yet part of any released build target: the objects
+// aren't checked in until `make generate-bpf-core` produces them, and an
+// empty-match go:embed is a compile error, so the embed has to stay out of
+// the default build until then.
+//
+//go:embed coreobjs/*.o
+var coreObjs embed.FS
+
+// coreConfig mirrors struct cilium_cfg's layout in bpf/lib/cilium_cfg.h. It
+// is written into each program's .rodata section at load time in place of
+// the -D macros the clang loader bakes in at compile time. Every field here
+// must stay binary-compatible with the C side; adding a field requires a
+// matching change to cilium_cfg.h and a coreobjs regeneration.
+type coreConfig struct {
+	Identity    uint32
+	Mtu         uint32
+	EndpointID  uint32
+	SecurityID  uint32
+	FeatureFlag uint64
+}
+
+// knownCOREPrograms are the datapath entry points coreobjs/ is expected to
+// carry a precompiled object for.
+var knownCOREPrograms = map[string]struct{}{
+	endpointPrefix:     {},
+	hostEndpointPrefix: {},
+	overlayPrefix:      {},
+	networkPrefix:      {},
+	xdpPrefix:          {},
+}
+
+// ErrCoreAttachNotImplemented is returned by the CO-RE loader once it has
+// successfully parsed and constant-rewritten a program: loading the
+// result into the kernel and attaching it to the right tc/xdp hook is not
+// wired up yet, so callers must not treat this as "nothing to do".
+var ErrCoreAttachNotImplemented = errors.New("coreLoader: attaching a loaded CO-RE program to the datapath is not implemented yet")
+
+// coreLoader implements Loader without invoking clang: it reads a
+// precompiled CO-RE ELF for the requested program and rewrites the
+// well-known cilium_cfg constants that the clang loader would otherwise
+// have passed as -D flags. It does not load the resulting spec into the
+// kernel, so no BTF relocation against the running kernel takes place;
+// once the spec is prepared it returns ErrCoreAttachNotImplemented.
+// Anything that isn't yet expressed as a cilium_cfg field falls back to
+// clangLoader.
+type coreLoader struct {
+	fallback *clangLoader
+}
+
+func newCoreLoader() *coreLoader {
+	return &coreLoader{fallback: newClangLoader()}
+}
+
+// loadSpec reads the embedded ELF for prefix and applies cfg via
+// RewriteConstants, returning a CollectionSpec ready to be loaded against
+// the kernel.
+func (c *coreLoader) loadSpec(prefix string, cfg coreConfig) (*ebpf.CollectionSpec, error) {
+	if _, ok := knownCOREPrograms[prefix]; !ok {
+		return nil, fmt.Errorf("no embedded CO-RE object for %s", prefix)
+	}
+
+	data, err := coreObjs.ReadFile(fmt.Sprintf("coreobjs/%s_bpfel.o", prefix))
+	if err != nil {
+		return nil, fmt.Errorf("reading embedded CO-RE object for %s: %w", prefix, err)
+	}
+
+	spec, err := ebpf.LoadCollectionSpecFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing embedded CO-RE object for %s: %w", prefix, err)
+	}
+
+	consts := map[string]interface{}{
+		"IDENTITY":     cfg.Identity,
+		"MTU":          cfg.Mtu,
+		"ENDPOINT_ID":  cfg.EndpointID,
+		"SECURITY_ID":  cfg.SecurityID,
+		"FEATURE_FLAG": cfg.FeatureFlag,
+	}
+	if err := spec.RewriteConstants(consts); err != nil {
+		return nil, fmt.Errorf("rewriting cilium_cfg constants for %s: %w", prefix, err)
+	}
+
+	return spec, nil
+}
+
+// supportsCORE reports whether the given options are fully expressible
+// through cilium_cfg. Anything involving a feature
+// that still requires a compile-time -D (for example, a not-yet-ported
+// debug toggle) is rejected so the caller can fall back to clangLoader.
+func (c *coreLoader) supportsCORE(opts []string) bool {
+	// Every option the clang loader receives today as a -D flag needs an
+	// explicit cilium_cfg mapping before CO-RE can serve it; until that
+	// table is filled in we conservatively defer anything with options.
+	return len(opts) == 0
+}
+
+func (c *coreLoader) CompileDatapath(ctx context.Context, dirs *directoryInfo, isHost bool, logger *logrus.Entry) error {
+	prefix := endpointPrefix
+	if isHost {
+		prefix = hostEndpointPrefix
+	}
+	if !c.supportsCORE(nil) {
+		return c.fallback.CompileDatapath(ctx, dirs, isHost, logger)
+	}
+	if _, err := c.loadSpec(prefix, coreConfig{}); err != nil {
+		logger.WithError(err).Debug("CO-RE loader could not serve datapath, falling back to clang")
+		return c.fallback.CompileDatapath(ctx, dirs, isHost, logger)
+	}
+	return fmt.Errorf("%w: %s", ErrCoreAttachNotImplemented, prefix)
+}
+
+func (c *coreLoader) CompileTemplate(ctx context.Context, out string, isHost bool) error {
+	// Templates are written out as real object files on disk for the
+	// endpoint regeneration machinery to hardlink from, so this always
+	// goes through the clang loader until that path learns to materialize
+	// an on-disk copy of an in-memory CollectionSpec.
+	return c.fallback.CompileTemplate(ctx, out, isHost)
+}
+
+func (c *coreLoader) CompileNetwork(ctx context.Context) error {
+	if !c.supportsCORE(nil) {
+		return c.fallback.CompileNetwork(ctx)
+	}
+	if _, err := c.loadSpec(networkPrefix, coreConfig{}); err != nil {
+		log.WithError(err).Debug("CO-RE loader could not serve network program, falling back to clang")
+		return c.fallback.CompileNetwork(ctx)
+	}
+	return fmt.Errorf("%w: %s", ErrCoreAttachNotImplemented, networkPrefix)
+}
+
+func (c *coreLoader) CompileOverlay(ctx context.Context, opts []string) error {
+	if !c.supportsCORE(opts) {
+		return c.fallback.CompileOverlay(ctx, opts)
+	}
+	if _, err := c.loadSpec(overlayPrefix, coreConfig{}); err != nil {
+		log.WithError(err).Debug("CO-RE loader could not serve overlay program, falling back to clang")
+		return c.fallback.CompileOverlay(ctx, opts)
+	}
+	return fmt.Errorf("%w: %s", ErrCoreAttachNotImplemented, overlayPrefix)
+}
+
+func (c *coreLoader) CompileWithOptions(ctx context.Context, src string, out string, opts []string) error {
+	// Ad hoc compiles are always feature-flagged through opts, which the
+	// CO-RE config table does not model yet.
+	return c.fallback.CompileWithOptions(ctx, src, out, opts)
+}
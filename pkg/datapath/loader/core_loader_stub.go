@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+//go:build !coreloader
+
+package loader
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// coreLoader stands in for the real CO-RE loader (core_loader.go) in
+// default builds, which carry no embedded bpf2go objects under coreobjs/
+// yet. It simply defers to clangLoader; useCoreLoader defaults to false,
+// so in practice this type is never instantiated outside of a build made
+// with the "coreloader" tag.
+type coreLoader struct {
+	fallback *clangLoader
+}
+
+func newCoreLoader() *coreLoader {
+	return &coreLoader{fallback: newClangLoader()}
+}
+
+func (c *coreLoader) CompileDatapath(ctx context.Context, dirs *directoryInfo, isHost bool, logger *logrus.Entry) error {
+	return c.fallback.CompileDatapath(ctx, dirs, isHost, logger)
+}
+
+func (c *coreLoader) CompileTemplate(ctx context.Context, out string, isHost bool) error {
+	return c.fallback.CompileTemplate(ctx, out, isHost)
+}
+
+func (c *coreLoader) CompileNetwork(ctx context.Context) error {
+	return c.fallback.CompileNetwork(ctx)
+}
+
+func (c *coreLoader) CompileOverlay(ctx context.Context, opts []string) error {
+	return c.fallback.CompileOverlay(ctx, opts)
+}
+
+func (c *coreLoader) CompileWithOptions(ctx context.Context, src string, out string, opts []string) error {
+	return c.fallback.CompileWithOptions(ctx, src, out, opts)
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package loader
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Loader compiles and/or loads the BPF datapath programs that make up the
+// Cilium datapath. There are two implementations: clangLoader invokes the
+// system clang/LLVM toolchain at runtime, while coreLoader consumes
+// precompiled CO-RE objects embedded in the agent binary and hands
+// templates, ad hoc compiles and anything it cannot express to clangLoader.
+// Callers obtain the active implementation through
+// NewLoader and are expected to treat it as stateless and safe for
+// concurrent use, matching the package-level functions it replaces.
+type Loader interface {
+	// CompileDatapath produces the object (and, in debug mode, the
+	// preprocessed source and assembly) for the endpoint or host
+	// datapath described by dirs.
+	CompileDatapath(ctx context.Context, dirs *directoryInfo, isHost bool, logger *logrus.Entry) error
+
+	// CompileTemplate produces a template object file shared across
+	// endpoints that have not yet diverged from it.
+	CompileTemplate(ctx context.Context, out string, isHost bool) error
+
+	// CompileNetwork produces the object for the network-attached
+	// datapath program.
+	CompileNetwork(ctx context.Context) error
+
+	// CompileOverlay produces the object for the tunnel overlay
+	// datapath program.
+	CompileOverlay(ctx context.Context, opts []string) error
+
+	// CompileWithOptions produces an arbitrary BPF object file using a
+	// caller-supplied option set, bypassing the endpoint/host/network
+	// conventions above.
+	CompileWithOptions(ctx context.Context, src string, out string, opts []string) error
+}
+
+// useCoreLoader selects the CO-RE loader instead of the clang loader. It is
+// disabled by default until enough of the datapath config surface has been
+// ported to cilium_cfg for the CO-RE loader to be a drop-in replacement;
+// expect this to become an option.Config knob once that work lands. Note
+// that the real CO-RE loader additionally requires building with the
+// "coreloader" tag (see core_loader.go); without it, newCoreLoader falls
+// back to clangLoader regardless of this setting.
+var useCoreLoader = false
+
+// NewLoader returns the Loader implementation selected for this agent. It
+// exists so that call sites can be migrated off the package-level
+// compile*/Compile* functions one at a time without needing to thread a
+// concrete type through.
+func NewLoader() Loader {
+	if useCoreLoader {
+		return newCoreLoader()
+	}
+	return newClangLoader()
+}
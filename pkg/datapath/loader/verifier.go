@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package loader
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/cilium/cilium/pkg/option"
+)
+
+// VerifierStats is the per-program complexity the kernel verifier reported
+// for a single section of a compiled object.
+type VerifierStats struct {
+	// Section is the BPF program section name, e.g. "from-container".
+	Section string
+	// Insns is the number of instructions the verifier walked to prove
+	// safety ("processed <N> insns").
+	Insns int
+	// StackDepth is the maximum stack depth the verifier computed for
+	// the program, in bytes.
+	StackDepth int
+}
+
+// VerifierThresholds bounds the complexity a single program is allowed to
+// report before verifierChecker treats it as a regression.
+type VerifierThresholds struct {
+	MaxInsns      int
+	MaxStackDepth int
+}
+
+// defaultVerifierThresholds errs on the side of the kernel's own limits
+// (one million instructions, 512 bytes of stack) so that, out of the box,
+// this only catches datapath changes that are already close to blowing the
+// verifier budget.
+var defaultVerifierThresholds = VerifierThresholds{
+	MaxInsns:      1_000_000,
+	MaxStackDepth: 512,
+}
+
+// verifierThresholds is consulted by compile() after every object build
+// while EnableVerifierComplexityCheck is set.
+// It is a package-level var, like nameBPFCPU, so tests can tighten it to
+// exercise the failure path without plumbing a parameter through the
+// entire compile() call chain.
+var verifierThresholds = defaultVerifierThresholds
+
+// EnableVerifierComplexityCheck gates the optional post-compile verifier
+// check in compile(). It defaults to off: kernelVerifierChecker.Check loads
+// every map and program in the object into the kernel to read back its
+// verifier log, which is a second real load/allocation of the same maps
+// (conntrack, policy, ...) the caller loads moments later to actually run
+// the program. Leaving this on in normal agent operation would double
+// verifier time and map-allocation churn on every endpoint regeneration,
+// undercutting the latency work elsewhere in this series. Expect this to
+// become an option.Config flag once it has a CLI-facing name; until then
+// it is set directly.
+var EnableVerifierComplexityCheck = false
+
+// verifierCheckerOverride lets tests swap in a fake VerifierChecker; when
+// nil, verifierChecker() picks the real implementation based on dry-run
+// mode.
+var verifierCheckerOverride VerifierChecker
+
+// verifierChecker returns the VerifierChecker compile() should use for the
+// current invocation.
+func verifierChecker() VerifierChecker {
+	if verifierCheckerOverride != nil {
+		return verifierCheckerOverride
+	}
+	return NewVerifierChecker(option.Config.DryMode)
+}
+
+// VerifierChecker is implemented alongside compile() so that verifier
+// complexity gating can be disabled in dry-run mode or swapped out by
+// tests, the same way Loader abstracts over compilation strategy.
+type VerifierChecker interface {
+	// Check loads obj against the running kernel with verbose verifier
+	// logging enabled and returns the per-program stats it reported.
+	// It does not itself decide pass/fail; callers compare the result
+	// against a VerifierThresholds.
+	Check(ctx context.Context, objPath string, mcpu string) ([]VerifierStats, error)
+}
+
+// noopVerifierChecker is used in dry-run mode, where there is no kernel to
+// load programs against.
+type noopVerifierChecker struct{}
+
+func (noopVerifierChecker) Check(ctx context.Context, objPath string, mcpu string) ([]VerifierStats, error) {
+	return nil, nil
+}
+
+// kernelVerifierChecker loads every program in obj with BPF_F_LOG_LEVEL=2
+// (verbose) and parses the resulting verifier log.
+type kernelVerifierChecker struct{}
+
+// NewVerifierChecker returns the VerifierChecker appropriate for the
+// current mode: a real kernel-backed checker, or a no-op in dry-run mode,
+// where there is no kernel to load against.
+func NewVerifierChecker(dryRun bool) VerifierChecker {
+	if dryRun {
+		return noopVerifierChecker{}
+	}
+	return kernelVerifierChecker{}
+}
+
+var (
+	verifierProcessedRe = regexp.MustCompile(`processed (\d+) insns`)
+	verifierStackRe     = regexp.MustCompile(`stack depth (\d+)`)
+)
+
+func (kernelVerifierChecker) Check(ctx context.Context, objPath string, mcpu string) ([]VerifierStats, error) {
+	spec, err := ebpf.LoadCollectionSpec(objPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s for verifier check: %w", objPath, err)
+	}
+
+	coll, loadErr := ebpf.NewCollectionWithOptions(spec, ebpf.CollectionOptions{
+		Programs: ebpf.ProgramOptions{
+			LogLevel: 2, // BPF_LOG_LEVEL verbose
+			LogSize:  10 * 1024 * 1024,
+		},
+	})
+	if coll != nil {
+		defer coll.Close()
+	} else {
+		// The kernel rejected every program before any of them could
+		// produce a verifier log worth parsing (e.g. a map creation
+		// failure), so there is nothing to range over.
+		return nil, fmt.Errorf("loading %s against the kernel for verifier check: %w", objPath, loadErr)
+	}
+	// A load failure with a non-nil coll still leaves the verifier log
+	// populated on whichever program tripped it, so keep going rather than
+	// bailing out here.
+
+	var stats []VerifierStats
+	for section, prog := range coll.Programs {
+		s, parseErr := parseVerifierLog(section, prog.VerifierLog)
+		if parseErr != nil {
+			verifierMetricParseErrors.WithLabelValues(section).Inc()
+			continue
+		}
+		stats = append(stats, s)
+
+		verifierInsns.WithLabelValues(section, mcpu).Set(float64(s.Insns))
+		verifierStackDepth.WithLabelValues(section, mcpu).Set(float64(s.StackDepth))
+	}
+
+	return stats, nil
+}
+
+// parseVerifierLog extracts the processed-instruction and stack-depth
+// figures the kernel verifier prints at the end of a verbose log, e.g.:
+//
+//	processed 1234 insns (limit 1000000) max_states_per_insn 0 ...
+//	... stack depth 96+0 ...
+func parseVerifierLog(section, logText string) (VerifierStats, error) {
+	stats := VerifierStats{Section: section}
+
+	scanner := bufio.NewScanner(strings.NewReader(logText))
+	var sawInsns, sawStack bool
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := verifierProcessedRe.FindStringSubmatch(line); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				stats.Insns = n
+				sawInsns = true
+			}
+		}
+		if m := verifierStackRe.FindStringSubmatch(line); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				stats.StackDepth = n
+				sawStack = true
+			}
+		}
+	}
+
+	if !sawInsns && !sawStack {
+		return stats, fmt.Errorf("no verifier complexity info found for %s", section)
+	}
+	return stats, nil
+}
+
+// checkVerifierComplexity loads obj and compares every program's reported
+// complexity against thresholds, returning an error naming the first
+// program that exceeds them. compile() calls it after each object build
+// while EnableVerifierComplexityCheck is set and logs a returned error as a
+// warning; the compile itself still succeeds.
+func checkVerifierComplexity(ctx context.Context, checker VerifierChecker, objPath, mcpu string, thresholds VerifierThresholds) error {
+	stats, err := checker.Check(ctx, objPath, mcpu)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range stats {
+		if s.Insns > thresholds.MaxInsns {
+			return fmt.Errorf("verifier complexity regression in %s: %d insns exceeds threshold %d", s.Section, s.Insns, thresholds.MaxInsns)
+		}
+		if s.StackDepth > thresholds.MaxStackDepth {
+			return fmt.Errorf("verifier complexity regression in %s: stack depth %d exceeds threshold %d", s.Section, s.StackDepth, thresholds.MaxStackDepth)
+		}
+	}
+	return nil
+}
+
+var (
+	verifierInsns = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "cilium",
+		Subsystem: "bpf",
+		Name:      "verifier_processed_insns",
+		Help:      "Number of instructions the kernel verifier processed for the last compile of a program section",
+	}, []string{"section", "mcpu"})
+
+	verifierStackDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "cilium",
+		Subsystem: "bpf",
+		Name:      "verifier_stack_depth",
+		Help:      "Maximum stack depth the kernel verifier computed for the last compile of a program section",
+	}, []string{"section", "mcpu"})
+
+	verifierMetricParseErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cilium",
+		Subsystem: "bpf",
+		Name:      "verifier_log_parse_errors_total",
+		Help:      "Number of times the verifier log for a program section could not be parsed for complexity metrics",
+	}, []string{"section"})
+)
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+// Package verifier compiles the datapath for every supported mcpu and
+// feeds the result through the same verifier-complexity gate the agent
+// applies at runtime, so that a complexity regression is caught in CI
+// instead of on a real node.
+package verifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/datapath/loader"
+	"github.com/cilium/cilium/pkg/option"
+)
+
+// mcpus mirrors the BPF ISA levels GetBPFCPU can select at runtime; this is
+// the production matrix loader.compile() actually varies the build over
+// (see standardCFlags/-mcpu in pkg/datapath/loader/compile.go).
+var mcpus = []string{"v1", "v2", "v3"}
+
+// kernelProfiles mirrors the minimum-kernel build matrix (54/510/61/netnext)
+// CI already uses for datapath compile tests. The backlog asked this
+// harness to iterate it too, but there is no compile-time knob in this
+// package that a kernel profile maps to today: loader.compile() doesn't
+// take one, and no bpf/*.c macro is keyed on a profile name (unlike mcpu,
+// which is a real -mcpu flag). Selecting a kernel profile changes which
+// kernel the *agent* runs against at test time, not what object gets
+// compiled, so there's nothing for CompileWithOptions to vary here yet.
+// Tracked as a gap rather than silently dropped: these subtests exist so
+// the missing dimension is visible in test output instead of just absent.
+var kernelProfiles = []string{"54", "510", "61", "netnext"}
+
+// TestVerifierComplexity compiles bpf_lxc.o for every mcpu in the
+// production matrix and asserts none of them exceed the default verifier
+// complexity thresholds.
+func TestVerifierComplexity(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping datapath compile in -short mode")
+	}
+
+	bpfDir := os.Getenv("CILIUM_BPF_DIR")
+	if bpfDir == "" {
+		t.Skip("CILIUM_BPF_DIR not set, skipping datapath verifier matrix")
+	}
+
+	stateDir := t.TempDir()
+	option.Config.BpfDir = bpfDir
+	option.Config.StateDir = stateDir
+
+	for _, mcpu := range mcpus {
+		mcpu := mcpu
+		t.Run(mcpu, func(t *testing.T) {
+			// CompileWithOptions writes into option.Config.StateDir itself
+			// (dirs.Output), so out must be a bare filename, not a path
+			// already rooted at stateDir.
+			out := "bpf_lxc_" + mcpu + ".o"
+			ctx := context.Background()
+
+			opts := []string{"-mcpu=" + mcpu}
+			if err := loader.CompileWithOptions(ctx, "bpf_lxc.c", out, opts); err != nil {
+				t.Fatalf("compiling bpf_lxc.c for mcpu=%s: %v", mcpu, err)
+			}
+
+			checker := loader.NewVerifierChecker(false)
+			stats, err := checker.Check(ctx, filepath.Join(stateDir, out), mcpu)
+			if err != nil {
+				t.Fatalf("verifier check for mcpu=%s: %v", mcpu, err)
+			}
+
+			for _, s := range stats {
+				if s.Insns > 1_000_000 {
+					t.Errorf("%s: %d insns exceeds the default threshold", s.Section, s.Insns)
+				}
+				if s.StackDepth > 512 {
+					t.Errorf("%s: stack depth %d exceeds the default threshold", s.Section, s.StackDepth)
+				}
+			}
+		})
+	}
+
+	for _, profile := range kernelProfiles {
+		profile := profile
+		t.Run("kernel-profile/"+profile, func(t *testing.T) {
+			t.Skip("no compile-time knob ties kernel profile " + profile +
+				" to a distinct build; see the kernelProfiles doc comment")
+		})
+	}
+}